@@ -0,0 +1,42 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command spdkcsi-controller runs only the CSI controller service: the
+// Deployment running this binary does not need host NVMe-oF/iSCSI tooling or
+// privileged access, only the SPDK RPC credentials.
+package main
+
+import (
+	"flag"
+
+	"k8s.io/klog"
+
+	"github.com/spdk/spdk-csi/pkg/spdk"
+)
+
+var (
+	endpoint = flag.String("endpoint", "unix://tmp/csi.sock", "CSI endpoint")
+	nodeID   = flag.String("nodeid", "", "node id")
+	version  = flag.String("version", "", "driver version")
+)
+
+func main() {
+	flag.Parse()
+
+	if err := spdk.Run(spdk.ControllerMode, *version, *nodeID, *endpoint); err != nil {
+		klog.Fatalln(err)
+	}
+}
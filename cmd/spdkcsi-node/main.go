@@ -0,0 +1,47 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command spdkcsi-node runs only the CSI node service: the DaemonSet running
+// this binary does not need cluster-wide SPDK RPC credentials, only host
+// NVMe-oF/iSCSI tooling and privileged access.
+package main
+
+import (
+	"flag"
+
+	"k8s.io/klog"
+
+	"github.com/spdk/spdk-csi/pkg/spdk"
+)
+
+var (
+	endpoint = flag.String("endpoint", "unix://tmp/csi.sock", "CSI endpoint")
+	// nodeID must match the name of the SPDK node (config.json Nodes[].name,
+	// see deploy/kubernetes/config-map.yaml) that this Kubernetes node is
+	// wired up to: it is advertised via NodeGetInfo as the
+	// topology.spdk.csi/node segment, which the controller's scheduler
+	// matches directly against its spdkNodes map keys.
+	nodeID = flag.String("nodeid", "", "node id, must match the SPDK node name (config.json Nodes[].name) this host is wired up to")
+	version  = flag.String("version", "", "driver version")
+)
+
+func main() {
+	flag.Parse()
+
+	if err := spdk.Run(spdk.NodeMode, *version, *nodeID, *endpoint); err != nil {
+		klog.Fatalln(err)
+	}
+}
@@ -20,46 +20,70 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
-	"k8s.io/klog"
 
 	csicommon "github.com/spdk/spdk-csi/pkg/csi-common"
 	"github.com/spdk/spdk-csi/pkg/util"
+	"github.com/spdk/spdk-csi/pkg/util/log"
 )
 
 var errVolumeInCreation = status.Error(codes.Internal, "volume in creation")
 
 type controllerServer struct {
 	*csicommon.DefaultControllerServer
-	spdkNodes   map[string]util.SpdkNode // all spdk nodes in cluster
-	volumeLocks *util.VolumeLocks
+	spdkNodes     map[string]util.SpdkNode // all spdk nodes in cluster
+	volumeLocks   *util.VolumeLocks
+	scheduler     Scheduler
+	metadataStore util.MetadataStore
+	kmsConfigs    map[string]util.KMSConfig
 }
 
 type spdkVolume struct {
 	lvolID   string
 	nodeName string
+	// publishID is the bdev actually passed to PublishVolume/UnpublishVolume/
+	// VolumeInfo: equal to lvolID, unless the volume is encrypted, in which
+	// case it's the crypto bdev layered over the lvol. DeleteVolume is always
+	// given lvolID.
+	publishID string
 }
 
-func (cs *controllerServer) CreateVolume(_ context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
+func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
 	volumeID := req.GetName()
-	unlock := cs.volumeLocks.Lock(volumeID)
-	defer unlock()
+	if acquired := cs.volumeLocks.TryAcquire(volumeID); !acquired {
+		log.DefaultLog(util.VolumeOperationAlreadyExistsFmt, volumeID)
+		return nil, status.Errorf(codes.Aborted, util.VolumeOperationAlreadyExistsFmt, volumeID)
+	}
+	defer cs.volumeLocks.Release(volumeID)
+
+	// createVolume overrides this to "found-existing" on the idempotent-retry
+	// path, so the audit interceptor's AuditRecord.Decision tells those two
+	// cases apart.
+	log.SetDecision(ctx, "created")
 
-	csiVolume, err := cs.createVolume(req)
+	csiVolume, err := cs.createVolume(ctx, req)
 	if err != nil {
-		klog.Errorf("failed to create volume, volumeID: %s err: %v", volumeID, err)
+		log.DefaultLog("failed to create volume, volumeID: %s err: %v", volumeID, err)
+		if status.Code(err) != codes.Unknown {
+			// createVolume returns a pre-formed status.Error for caller-input
+			// violations (e.g. an unsupported access mode); don't relabel
+			// those as codes.Internal.
+			return nil, err
+		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
 	volumeInfo, err := cs.publishVolume(csiVolume.GetVolumeId())
 	if err != nil {
-		klog.Errorf("failed to publish volume, volumeID: %s err: %v", volumeID, err)
+		log.DefaultLog("failed to publish volume, volumeID: %s err: %v", volumeID, err)
 		cs.deleteVolume(csiVolume.GetVolumeId()) //nolint:errcheck // we can do little
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -77,29 +101,33 @@ func (cs *controllerServer) CreateVolume(_ context.Context, req *csi.CreateVolum
 
 func (cs *controllerServer) DeleteVolume(_ context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
 	volumeID := req.GetVolumeId()
-	unlock := cs.volumeLocks.Lock(volumeID)
-	defer unlock()
+	if acquired := cs.volumeLocks.TryAcquire(volumeID); !acquired {
+		log.DefaultLog(util.VolumeOperationAlreadyExistsFmt, volumeID)
+		return nil, status.Errorf(codes.Aborted, util.VolumeOperationAlreadyExistsFmt, volumeID)
+	}
+	defer cs.volumeLocks.Release(volumeID)
 	// no harm if volume already unpublished
 	err := cs.unpublishVolume(volumeID)
 	switch {
 	case errors.Is(err, util.ErrVolumeUnpublished):
 		// unpublished but not deleted in last request?
-		klog.Warningf("volume not published: %s", volumeID)
+		log.DefaultLog("volume not published: %s", volumeID)
 	case errors.Is(err, util.ErrVolumeDeleted):
 		// deleted in previous request?
-		klog.Warningf("volume already deleted: %s", volumeID)
+		log.DefaultLog("volume already deleted: %s", volumeID)
 	case err != nil:
-		klog.Errorf("failed to unpublish volume, volumeID: %s err: %v", volumeID, err)
+		log.DefaultLog("failed to unpublish volume, volumeID: %s err: %v", volumeID, err)
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
 	// no harm if volume already deleted
 	err = cs.deleteVolume(volumeID)
-	if errors.Is(err, util.ErrJSONNoSuchDevice) {
+	switch {
+	case errors.Is(err, util.ErrJSONNoSuchDevice), errors.Is(err, util.ErrVolumeDeleted):
 		// deleted in previous request?
-		klog.Warningf("volume not exists: %s", volumeID)
-	} else if err != nil {
-		klog.Errorf("failed to delete volume, volumeID: %s err: %v", volumeID, err)
+		log.DefaultLog("volume not exists: %s", volumeID)
+	case err != nil:
+		log.DefaultLog("failed to delete volume, volumeID: %s err: %v", volumeID, err)
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
@@ -129,41 +157,59 @@ func (cs *controllerServer) ValidateVolumeCapabilities(_ context.Context, req *c
 
 func (cs *controllerServer) CreateSnapshot(_ context.Context, req *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
 	volumeID := req.GetSourceVolumeId()
-	unlock := cs.volumeLocks.Lock(volumeID)
-	defer unlock()
+	if acquired := cs.volumeLocks.TryAcquire(volumeID); !acquired {
+		log.DefaultLog(util.VolumeOperationAlreadyExistsFmt, volumeID)
+		return nil, status.Errorf(codes.Aborted, util.VolumeOperationAlreadyExistsFmt, volumeID)
+	}
+	defer cs.volumeLocks.Release(volumeID)
 
 	snapshotName := req.GetName()
-	spdkVol, err := getSPDKVol(volumeID)
+	spdkVol, err := cs.getSPDKVol(volumeID)
 	if err != nil {
-		klog.Errorf("failed to get spdk volume, volumeID: %s err: %v", volumeID, err)
+		log.DefaultLog("failed to get spdk volume, volumeID: %s err: %v", volumeID, err)
 		return nil, err
 	}
 
 	snapshotID, err := cs.spdkNodes[spdkVol.nodeName].CreateSnapshot(spdkVol.lvolID, snapshotName)
 	if err != nil {
-		klog.Errorf("failed to create snapshot, volumeID: %s snapshotName: %s err: %v", volumeID, snapshotName, err)
+		log.DefaultLog("failed to create snapshot, volumeID: %s snapshotName: %s err: %v", volumeID, snapshotName, err)
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
 	volInfo, err := cs.spdkNodes[spdkVol.nodeName].VolumeInfo(spdkVol.lvolID)
 	if err != nil {
-		klog.Errorf("failed to get volume info, volumeID: %s err: %v", volumeID, err)
+		log.DefaultLog("failed to get volume info, volumeID: %s err: %v", volumeID, err)
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 	size, err := strconv.ParseInt(volInfo["lvolSize"], 10, 64)
 	if err != nil {
-		klog.Errorf("failed to parse volume size, lvolSize: %s err: %v", volInfo["lvolSize"], err)
+		log.DefaultLog("failed to parse volume size, lvolSize: %s err: %v", volInfo["lvolSize"], err)
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 	creationTime := timestamppb.Now()
+	csiSnapshotID := fmt.Sprintf("%s:%s", spdkVol.nodeName, snapshotID)
 	snapshotData := csi.Snapshot{
 		SizeBytes:      size,
-		SnapshotId:     fmt.Sprintf("%s:%s", spdkVol.nodeName, snapshotID),
+		SnapshotId:     csiSnapshotID,
 		SourceVolumeId: spdkVol.lvolID,
 		CreationTime:   creationTime,
 		ReadyToUse:     true,
 	}
 
+	// carry the source volume's DEK reference forward so an encrypted clone
+	// made from this snapshot can recover the same key.
+	if srcMeta, metaErr := cs.metadataStore.Get(volumeID); metaErr == nil && srcMeta.Encrypted {
+		if err := cs.metadataStore.Create(csiSnapshotID, util.VolumeMetadata{
+			NodeName:  spdkVol.nodeName,
+			LvolUUID:  snapshotID,
+			Encrypted: true,
+			KMSID:     srcMeta.KMSID,
+			KeyHandle: srcMeta.KeyHandle,
+		}); err != nil {
+			log.DefaultLog("failed to persist DEK reference for snapshot %s: %v", csiSnapshotID, err)
+		}
+	}
+
 	return &csi.CreateSnapshotResponse{
 		Snapshot: &snapshotData,
 	}, nil
@@ -171,28 +217,117 @@ func (cs *controllerServer) CreateSnapshot(_ context.Context, req *csi.CreateSna
 
 func (cs *controllerServer) DeleteSnapshot(_ context.Context, req *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
 	snapshotID := req.GetSnapshotId()
-	unlock := cs.volumeLocks.Lock(snapshotID)
-	defer unlock()
+	if acquired := cs.volumeLocks.TryAcquire(snapshotID); !acquired {
+		log.DefaultLog(util.VolumeOperationAlreadyExistsFmt, snapshotID)
+		return nil, status.Errorf(codes.Aborted, util.VolumeOperationAlreadyExistsFmt, snapshotID)
+	}
+	defer cs.volumeLocks.Release(snapshotID)
 
-	spdkVol, err := getSPDKVol(snapshotID)
+	spdkVol, err := cs.getSPDKVol(snapshotID)
 	if err != nil {
-		klog.Errorf("failed to get spdk volume, snapshotID: %s err: %v", snapshotID, err)
+		log.DefaultLog("failed to get spdk volume, snapshotID: %s err: %v", snapshotID, err)
 		return nil, err
 	}
 
 	err = cs.spdkNodes[spdkVol.nodeName].DeleteVolume(spdkVol.lvolID)
 	if err != nil {
-		klog.Errorf("failed to delete snapshot, snapshotID: %s err: %v", snapshotID, err)
+		log.DefaultLog("failed to delete snapshot, snapshotID: %s err: %v", snapshotID, err)
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	if err := cs.metadataStore.Delete(snapshotID); err != nil {
+		log.DefaultLog("failed to delete DEK reference for snapshot %s: %v", snapshotID, err)
+	}
 
 	return &csi.DeleteSnapshotResponse{}, nil
 }
 
-func (cs *controllerServer) createVolume(req *csi.CreateVolumeRequest) (*csi.Volume, error) {
+func (cs *controllerServer) ControllerExpandVolume(_ context.Context, req *csi.ControllerExpandVolumeRequest) (*csi.ControllerExpandVolumeResponse, error) {
+	volumeID := req.GetVolumeId()
+	if acquired := cs.volumeLocks.TryAcquire(volumeID); !acquired {
+		log.DefaultLog(util.VolumeOperationAlreadyExistsFmt, volumeID)
+		return nil, status.Errorf(codes.Aborted, util.VolumeOperationAlreadyExistsFmt, volumeID)
+	}
+	defer cs.volumeLocks.Release(volumeID)
+
+	spdkVol, err := cs.getSPDKVol(volumeID)
+	if err != nil {
+		log.DefaultLog("failed to get spdk volume, volumeID: %s err: %v", volumeID, err)
+		return nil, err
+	}
+	spdkNode := cs.spdkNodes[spdkVol.nodeName]
+
+	sizeMiB := util.ToMiB(req.GetCapacityRange().GetRequiredBytes())
+
+	volInfo, err := spdkNode.VolumeInfo(spdkVol.lvolID)
+	if err != nil {
+		log.DefaultLog("failed to get volume info, volumeID: %s err: %v", volumeID, err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	currentSizeBytes, err := strconv.ParseInt(volInfo["lvolSize"], 10, 64)
+	if err != nil {
+		log.DefaultLog("failed to parse volume size, lvolSize: %s err: %v", volInfo["lvolSize"], err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	// lvolSize is reported in bytes (see CreateSnapshot), but sizeMiB above is
+	// already converted, so the two must be put in the same unit before
+	// comparing.
+	currentSizeMiB := util.ToMiB(currentSizeBytes)
+	if sizeMiB < currentSizeMiB {
+		return nil, status.Errorf(codes.OutOfRange, "shrinking volume %s from %d MiB to %d MiB is not supported", volumeID, currentSizeMiB, sizeMiB)
+	}
+	if sizeMiB == currentSizeMiB {
+		return &csi.ControllerExpandVolumeResponse{CapacityBytes: sizeMiB * 1024 * 1024, NodeExpansionRequired: true}, nil
+	}
+
+	lvStores, err := spdkNode.LvStores()
+	if err != nil {
+		log.DefaultLog("failed to get lvstores, volumeID: %s err: %v", volumeID, err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	var freeSizeMiB int64
+	for i := range lvStores {
+		if lvStores[i].Name == volInfo["lvstore"] {
+			freeSizeMiB = lvStores[i].FreeSizeMiB
+			break
+		}
+	}
+	if sizeMiB-currentSizeMiB > freeSizeMiB {
+		return nil, status.Errorf(codes.OutOfRange, "requested size %d MiB exceeds lvstore %s free space %d MiB", sizeMiB, volInfo["lvstore"], freeSizeMiB)
+	}
+
+	if err := spdkNode.ResizeVolume(spdkVol.lvolID, sizeMiB); err != nil {
+		log.DefaultLog("failed to resize volume, volumeID: %s err: %v", volumeID, err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &csi.ControllerExpandVolumeResponse{
+		CapacityBytes:         sizeMiB * 1024 * 1024,
+		NodeExpansionRequired: true,
+	}, nil
+}
+
+func (cs *controllerServer) ControllerGetCapabilities(_ context.Context, _ *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
+	toCap := func(c csi.ControllerServiceCapability_RPC_Type) *csi.ControllerServiceCapability {
+		return &csi.ControllerServiceCapability{
+			Type: &csi.ControllerServiceCapability_Rpc{
+				Rpc: &csi.ControllerServiceCapability_RPC{Type: c},
+			},
+		}
+	}
+	return &csi.ControllerGetCapabilitiesResponse{
+		Capabilities: []*csi.ControllerServiceCapability{
+			toCap(csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME),
+			toCap(csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT),
+			toCap(csi.ControllerServiceCapability_RPC_CLONE_VOLUME),
+			toCap(csi.ControllerServiceCapability_RPC_EXPAND_VOLUME),
+		},
+	}, nil
+}
+
+func (cs *controllerServer) createVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.Volume, error) {
 	size := req.GetCapacityRange().GetRequiredBytes()
 	if size == 0 {
-		klog.Warningln("invalid volume size, resize to 1G")
+		log.DefaultLog("invalid volume size, resize to 1G")
 		size = 1024 * 1024 * 1024
 	}
 	sizeMiB := util.ToMiB(size)
@@ -202,7 +337,27 @@ func (cs *controllerServer) createVolume(req *csi.CreateVolumeRequest) (*csi.Vol
 		ContentSource: req.GetVolumeContentSource(),
 	}
 
-	// check all SPDK nodes to see if the volume has already been created
+	encrypted := req.GetParameters()["encrypted"] == "true"
+	if encrypted {
+		// the crypto bdev can only be attached from the controller that
+		// created it, so a volume exported to more than one node at a time
+		// can't be made readable there.
+		for _, vcap := range req.GetVolumeCapabilities() {
+			switch vcap.GetAccessMode().GetMode() {
+			case csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY,
+				csi.VolumeCapability_AccessMode_MULTI_NODE_SINGLE_WRITER,
+				csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER:
+				return nil, status.Error(codes.InvalidArgument, "encrypted volumes do not support multi-node access modes")
+			}
+		}
+	}
+
+	// check all SPDK nodes to see if the volume has already been created.
+	// this is an idempotent-retry path (CSI requires CreateVolume to return
+	// the same VolumeID on every retry for a given Name), so look up the
+	// opaque VolumeID it was originally minted under instead of re-deriving
+	// the legacy "node:lvol" encoding, which would hand back a different ID
+	// than the first call returned and orphan the original metadata/DEK.
 	for nodeName, node := range cs.spdkNodes {
 		lvStores, err := node.LvStores()
 		if err != nil {
@@ -211,7 +366,8 @@ func (cs *controllerServer) createVolume(req *csi.CreateVolumeRequest) (*csi.Vol
 		for lvsIdx := range lvStores {
 			volumeID, err := node.GetVolume(req.GetName(), lvStores[lvsIdx].Name)
 			if err == nil {
-				vol.VolumeId = fmt.Sprintf("%s:%s", nodeName, volumeID)
+				log.SetDecision(ctx, "found-existing")
+				vol.VolumeId = cs.findVolumeIDByName(req.GetName(), nodeName, volumeID)
 				return &vol, nil
 			}
 		}
@@ -220,63 +376,221 @@ func (cs *controllerServer) createVolume(req *csi.CreateVolumeRequest) (*csi.Vol
 	// if volume content source is specified, using the same node/lvstore as the source volume.
 	var err error
 	var volumeID string
+	var nodeName, lvstore, sourceSnapshot string
 	if req.GetVolumeContentSource() == nil {
-		// schedule suitable node:lvstore
-		nodeName, lvstore, err2 := cs.schedule(sizeMiB)
-		if err2 != nil {
-			return nil, err2
+		sr := scheduleRequestFromCSI(req, sizeMiB)
+		// optimistic concurrency control: if CreateVolume races with another
+		// allocation on the same lvstore and loses, blacklist it and ask the
+		// scheduler for another candidate, up to maxScheduleRetries times.
+		for attempt := 0; ; attempt++ {
+			nodeName, lvstore, err = cs.scheduler.Schedule(cs.spdkNodes, sr)
+			if err != nil {
+				if sr.node != "" {
+					if _, ok := cs.spdkNodes[sr.node]; !ok {
+						// sr.node came from the topology.spdk.csi/node segment a node
+						// plugin advertised via NodeGetInfo, which must equal a
+						// config.json Nodes[].name key (see newNodeServer); it doesn't
+						// match any configured SPDK node, so the real problem is a
+						// --nodeid/config.json mismatch, not a capacity shortfall.
+						return nil, fmt.Errorf("topology.spdk.csi/node %q does not match any configured SPDK node name; check that the node plugin's --nodeid matches a Nodes[].name entry in config.json: %w", sr.node, err)
+					}
+				}
+				return nil, err
+			}
+			volumeID, err = cs.spdkNodes[nodeName].CreateVolume(req.GetName(), lvstore, sizeMiB)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, util.ErrJSONNoSpaceLeft) || attempt == maxScheduleRetries {
+				return nil, err
+			}
+			log.DefaultLog("lost race for space on %s:%s, re-scheduling (attempt %d/%d)", nodeName, lvstore, attempt+1, maxScheduleRetries)
+			if sr.blacklist == nil {
+				sr.blacklist = map[string]bool{}
+			}
+			sr.blacklist[fmt.Sprintf("%s:%s", nodeName, lvstore)] = true
 		}
-		// TODO: re-schedule on ErrJSONNoSpaceLeft per optimistic concurrency control
-		// create a new volume
-		volumeID, err = cs.spdkNodes[nodeName].CreateVolume(req.GetName(), lvstore, sizeMiB)
-		// in the subsequent DeleteVolume() request, a nodeName needs to be specified,
-		// but the current CSI mechanism only passes the VolumeId to DeleteVolume().
-		// therefore, the nodeName is included as part of the VolumeId.
-		vol.VolumeId = fmt.Sprintf("%s:%s", nodeName, volumeID)
 	} else {
 		// find the node/lvstore of the specified content source volume
-		nodeName, lvstore, sourceLvolID, err2 := cs.getSnapshotInfo(req.GetVolumeContentSource())
-		if err2 != nil {
-			return nil, err2
+		var sourceLvolID string
+		nodeName, lvstore, sourceLvolID, err = cs.getSnapshotInfo(req.GetVolumeContentSource())
+		if err != nil {
+			return nil, err
 		}
 		// create a volume cloned from the source volume
 		volumeID, err = cs.spdkNodes[nodeName].CloneVolume(req.GetName(), lvstore, sourceLvolID)
-		vol.VolumeId = fmt.Sprintf("%s:%s", nodeName, volumeID)
+		sourceSnapshot = req.GetVolumeContentSource().GetSnapshot().GetSnapshotId()
 	}
 
 	if err != nil {
 		return nil, err
 	}
+
+	meta := util.VolumeMetadata{
+		Name:           req.GetName(),
+		NodeName:       nodeName,
+		LvStore:        lvstore,
+		LvolUUID:       volumeID,
+		SizeMiB:        sizeMiB,
+		SourceSnapshot: sourceSnapshot,
+	}
+	if encrypted {
+		kmsID, keyHandle, cryptoBdevName, encErr := cs.setupEncryption(req, nodeName, volumeID)
+		if encErr != nil {
+			cs.spdkNodes[nodeName].DeleteVolume(volumeID) //nolint:errcheck // we can do little
+			return nil, encErr
+		}
+		meta.Encrypted = true
+		meta.KMSID = kmsID
+		meta.KeyHandle = keyHandle
+		meta.CryptoBdevName = cryptoBdevName
+	}
+
+	// mint an opaque VolumeID and persist the node/lvstore/lvol it maps to,
+	// instead of leaking SPDK topology into the CSI VolumeID.
+	csiVolumeID := uuid.NewString()
+	if err := cs.metadataStore.Create(csiVolumeID, meta); err != nil {
+		if encrypted {
+			cs.spdkNodes[nodeName].DeleteCryptoBdev(meta.CryptoBdevName) //nolint:errcheck // we can do little
+		}
+		cs.spdkNodes[nodeName].DeleteVolume(volumeID) //nolint:errcheck // we can do little
+		return nil, fmt.Errorf("failed to persist metadata for volume %s: %w", csiVolumeID, err)
+	}
+	vol.VolumeId = csiVolumeID
+
 	return &vol, nil
 }
 
-func getSPDKVol(csiVolumeID string) (*spdkVolume, error) {
-	// extract spdkNodeName and spdkLvolID from csiVolumeID
-	// csiVolumeID: node001:8e2dcb9d-3a79-4362-965e-fdb0cd3f4b8d
-	// spdkNodeName: node001
-	// spdklvolID: 8e2dcb9d-3a79-4362-965e-fdb0cd3f4b8d
+// setupEncryption generates (or, for an encrypted clone, recovers) the DEK
+// for a freshly created lvol and layers an SPDK crypto bdev over it, so that
+// PublishVolume exports the encrypted view instead of the raw lvol.
+func (cs *controllerServer) setupEncryption(req *csi.CreateVolumeRequest, nodeName, lvolID string) (kmsID, keyHandle, cryptoBdevName string, err error) {
+	kmsID = req.GetParameters()["encryptionKMSID"]
+	if kmsID == "" {
+		return "", "", "", fmt.Errorf("encryptionKMSID parameter is required when encrypted=true")
+	}
+	kmsProvider, err := util.NewKMS(kmsID, cs.kmsConfigs)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	var dek []byte
+	if snapshot := req.GetVolumeContentSource().GetSnapshot(); snapshot != nil {
+		// clones must carry the same DEK reference as their source snapshot
+		// so the cloned lvol's ciphertext remains readable.
+		snapMeta, metaErr := cs.metadataStore.Get(snapshot.GetSnapshotId())
+		if metaErr != nil || !snapMeta.Encrypted {
+			return "", "", "", fmt.Errorf("source snapshot %s is not encrypted, cannot create an encrypted clone of it", snapshot.GetSnapshotId())
+		}
+		keyHandle = snapMeta.KeyHandle
+		dek, err = kmsProvider.GetDEK(keyHandle)
+	} else {
+		keyHandle, dek, err = kmsProvider.GenerateDEK()
+	}
+	if err != nil {
+		return "", "", "", err
+	}
 
+	cryptoBdevName, err = cs.spdkNodes[nodeName].CreateCryptoBdev(lvolID, dek)
+	if err != nil {
+		return "", "", "", err
+	}
+	return kmsID, keyHandle, cryptoBdevName, nil
+}
+
+// dekStillReferenced reports whether any remaining volume or snapshot record
+// still references meta's (KMSID, KeyHandle) pair. CreateSnapshot and cloning
+// an encrypted volume both carry the source's KeyHandle forward instead of
+// wrapping a new DEK, so deleteVolume must not destroy a DEK that a sibling
+// snapshot or clone still needs to decrypt its data. Callers must delete
+// their own metadata record before calling this, or two volumes sharing a
+// DEK deleted concurrently could each see the other still present and both
+// leave the DEK behind.
+func (cs *controllerServer) dekStillReferenced(meta util.VolumeMetadata) (bool, error) {
+	records, err := cs.metadataStore.List()
+	if err != nil {
+		return false, err
+	}
+	for _, other := range records {
+		if other.Encrypted && other.KMSID == meta.KMSID && other.KeyHandle == meta.KeyHandle {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// findVolumeIDByName returns the opaque CSI VolumeID a prior CreateVolume(name)
+// call minted, by searching the metadata store for a record whose Name
+// matches and whose node/lvol matches the SPDK-side volume just found by
+// name. If no such record exists, the volume predates the metadata store, so
+// the legacy "node:lvol" encoding is returned instead, exactly as it would
+// have been returned by the original call.
+func (cs *controllerServer) findVolumeIDByName(name, nodeName, lvolID string) string {
+	records, err := cs.metadataStore.List()
+	if err != nil {
+		log.DefaultLog("failed to list volume metadata while resolving idempotent create for %s: %v", name, err)
+		return fmt.Sprintf("%s:%s", nodeName, lvolID)
+	}
+	for csiVolumeID, meta := range records {
+		if meta.Name == name && meta.NodeName == nodeName && meta.LvolUUID == lvolID {
+			return csiVolumeID
+		}
+	}
+	return fmt.Sprintf("%s:%s", nodeName, lvolID)
+}
+
+// getSPDKVol resolves a CSI VolumeID/SnapshotID to the SPDK node/lvol backing
+// it. Opaque UUID IDs minted since the metadata store was introduced are
+// looked up there; IDs that predate the metadata store still use the legacy
+// "node:lvol" format and are parsed directly, the same way other CSI drivers
+// have handled VolumeID format migrations across versions.
+func (cs *controllerServer) getSPDKVol(csiVolumeID string) (*spdkVolume, error) {
+	meta, err := cs.metadataStore.Get(csiVolumeID)
+	if err == nil {
+		publishID := meta.LvolUUID
+		if meta.CryptoBdevName != "" {
+			publishID = meta.CryptoBdevName
+		}
+		return &spdkVolume{
+			nodeName:  meta.NodeName,
+			lvolID:    meta.LvolUUID,
+			publishID: publishID,
+		}, nil
+	}
+	if !errors.Is(err, util.ErrMetadataNotFound) {
+		return nil, err
+	}
+
+	// legacy format: node001:8e2dcb9d-3a79-4362-965e-fdb0cd3f4b8d
 	ids := strings.Split(csiVolumeID, ":")
 	if len(ids) == 2 {
 		return &spdkVolume{
-			nodeName: ids[0],
-			lvolID:   ids[1],
+			nodeName:  ids[0],
+			lvolID:    ids[1],
+			publishID: ids[1],
 		}, nil
 	}
-	return nil, fmt.Errorf("missing nodeName in volume: %s", csiVolumeID)
+
+	// an opaque VolumeID with no metadata record and no legacy "node:lvol"
+	// encoding to fall back on: deleteVolume removes the metadata record only
+	// after the lvol itself has been deleted, so this means the volume was
+	// already deleted by a previous call. Report it the same way as any
+	// other already-deleted volume so a retried DeleteVolume is idempotent
+	// instead of failing with codes.Internal forever.
+	return nil, util.ErrVolumeDeleted
 }
 
 func (cs *controllerServer) publishVolume(volumeID string) (map[string]string, error) {
-	spdkVol, err := getSPDKVol(volumeID)
+	spdkVol, err := cs.getSPDKVol(volumeID)
 	if err != nil {
 		return nil, err
 	}
-	err = cs.spdkNodes[spdkVol.nodeName].PublishVolume(spdkVol.lvolID)
+	err = cs.spdkNodes[spdkVol.nodeName].PublishVolume(spdkVol.publishID)
 	if err != nil {
 		return nil, err
 	}
 
-	volumeInfo, err := cs.spdkNodes[spdkVol.nodeName].VolumeInfo(spdkVol.lvolID)
+	volumeInfo, err := cs.spdkNodes[spdkVol.nodeName].VolumeInfo(spdkVol.publishID)
 	if err != nil {
 		cs.unpublishVolume(volumeID) //nolint:errcheck // we can do little
 		return nil, err
@@ -285,19 +599,56 @@ func (cs *controllerServer) publishVolume(volumeID string) (map[string]string, e
 }
 
 func (cs *controllerServer) deleteVolume(volumeID string) error {
-	spdkVol, err := getSPDKVol(volumeID)
+	spdkVol, err := cs.getSPDKVol(volumeID)
 	if err != nil {
 		return err
 	}
-	return cs.spdkNodes[spdkVol.nodeName].DeleteVolume(spdkVol.lvolID)
+	spdkNode := cs.spdkNodes[spdkVol.nodeName]
+
+	meta, metaErr := cs.metadataStore.Get(volumeID)
+	if metaErr == nil && meta.Encrypted {
+		if err := spdkNode.DeleteCryptoBdev(meta.CryptoBdevName); err != nil {
+			return fmt.Errorf("failed to delete crypto bdev %s: %w", meta.CryptoBdevName, err)
+		}
+	}
+
+	if err := spdkNode.DeleteVolume(spdkVol.lvolID); err != nil {
+		return err
+	}
+
+	// delete this volume's own metadata record before checking whether its
+	// DEK is still referenced elsewhere: two volumes sharing a DEK hold
+	// separate volume locks, so their deletes can run concurrently, and
+	// dekStillReferenced must see its caller's own record already gone or a
+	// race where both sides see each other still present would leak the DEK.
+	if err := cs.metadataStore.Delete(volumeID); err != nil {
+		log.DefaultLog("failed to delete metadata for volume %s: %v", volumeID, err)
+	}
+
+	if metaErr == nil && meta.Encrypted {
+		if shared, err := cs.dekStillReferenced(meta); err != nil {
+			log.DefaultLog("failed to check DEK references for volume %s, DEK %s left behind: %v", volumeID, meta.KeyHandle, err)
+		} else if shared {
+			log.DefaultLog("DEK %s for volume %s is still referenced by another volume/snapshot, not destroying", meta.KeyHandle, volumeID)
+		} else {
+			kms, err := util.NewKMS(meta.KMSID, cs.kmsConfigs)
+			if err != nil {
+				log.DefaultLog("failed to resolve KMS for volume %s, DEK %s left behind: %v", volumeID, meta.KeyHandle, err)
+			} else if err := kms.DestroyDEK(meta.KeyHandle); err != nil {
+				log.DefaultLog("failed to destroy DEK for volume %s: %v", volumeID, err)
+			}
+		}
+	}
+
+	return nil
 }
 
 func (cs *controllerServer) unpublishVolume(volumeID string) error {
-	spdkVol, err := getSPDKVol(volumeID)
+	spdkVol, err := cs.getSPDKVol(volumeID)
 	if err != nil {
 		return err
 	}
-	return cs.spdkNodes[spdkVol.nodeName].UnpublishVolume(spdkVol.lvolID)
+	return cs.spdkNodes[spdkVol.nodeName].UnpublishVolume(spdkVol.publishID)
 }
 
 func (cs *controllerServer) getSnapshotInfo(vcs *csi.VolumeContentSource) (
@@ -309,7 +660,7 @@ func (cs *controllerServer) getSnapshotInfo(vcs *csi.VolumeContentSource) (
 		err = fmt.Errorf("invalid volume content source, only snapshot source is supported")
 		return
 	}
-	snapSpdkVol, err := getSPDKVol(snapshotSource.GetSnapshotId())
+	snapSpdkVol, err := cs.getSPDKVol(snapshotSource.GetSnapshotId())
 	if err != nil {
 		return
 	}
@@ -324,26 +675,27 @@ func (cs *controllerServer) getSnapshotInfo(vcs *csi.VolumeContentSource) (
 	return
 }
 
-// simplest volume scheduler: find first node:lvstore with enough free space
-func (cs *controllerServer) schedule(sizeMiB int64) (nodeName, lvstore string, err error) {
-	for name, spdkNode := range cs.spdkNodes {
-		// retrieve latest lvstore info from spdk node
-		lvstores, err := spdkNode.LvStores()
-		if err != nil {
-			klog.Errorf("failed to get lvstores from node %s: %s", spdkNode.Info(), err.Error())
-			continue
-		}
-		// check if lvstore has enough free space
-		for i := range lvstores {
-			lvstore := &lvstores[i]
-			if lvstore.FreeSizeMiB > sizeMiB {
-				return name, lvstore.Name, nil
-			}
+// scheduleRequestFromCSI derives a scheduleRequest from the CSI CreateVolumeRequest,
+// honoring the topology.spdk.csi/node accessibility requirement populated by the
+// node plugin and the pool/lvstore StorageClass parameter.
+func scheduleRequestFromCSI(req *csi.CreateVolumeRequest, sizeMiB int64) scheduleRequest {
+	sr := scheduleRequest{sizeMiB: sizeMiB}
+
+	params := req.GetParameters()
+	if pool := params["pool"]; pool != "" {
+		sr.pool = pool
+	} else {
+		sr.pool = params["lvstore"]
+	}
+
+	for _, topo := range req.GetAccessibilityRequirements().GetRequisite() {
+		if node := topo.GetSegments()[topologyNodeKey]; node != "" {
+			sr.node = node
+			break
 		}
-		klog.Infof("not enough free space from node %s", spdkNode.Info())
 	}
 
-	return "", "", fmt.Errorf("failed to find node with enough free space")
+	return sr
 }
 
 func newControllerServer(d *csicommon.CSIDriver) (*controllerServer, error) {
@@ -362,12 +714,45 @@ func newControllerServer(d *csicommon.CSIDriver) (*controllerServer, error) {
 			TargetType string `json:"targetType"`
 			TargetAddr string `json:"targetAddr"`
 		} `json:"Nodes"`
+		// SchedulerStrategy selects the volume placement strategy: "best-fit"
+		// (default, reduces fragmentation) or "max-free" (spreads volumes
+		// across the least-loaded node).
+		SchedulerStrategy string `json:"schedulerStrategy"`
+		// MetadataStore configures where the volumeID->node/lvstore/lvol
+		// mapping is persisted. Type defaults to "configmap".
+		MetadataStore struct {
+			Type               string   `json:"type"`
+			ConfigMapName      string   `json:"configMapName"`
+			ConfigMapNamespace string   `json:"configMapNamespace"`
+			EtcdEndpoints      []string `json:"etcdEndpoints"`
+			JSONFilePath       string   `json:"jsonFilePath"`
+		} `json:"metadataStore"`
 	}
 	configFile := util.FromEnv("SPDKCSI_CONFIG", "/etc/spdkcsi-config/config.json")
 	err := util.ParseJSONFile(configFile, &config)
 	if err != nil {
 		return nil, err
 	}
+	server.scheduler = newScheduler(config.SchedulerStrategy)
+
+	server.metadataStore, err = newMetadataStore(config.MetadataStore.Type,
+		config.MetadataStore.ConfigMapName, config.MetadataStore.ConfigMapNamespace,
+		config.MetadataStore.EtcdEndpoints, config.MetadataStore.JSONFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize metadata store: %w", err)
+	}
+
+	// kms-config is optional: encryption is opt-in per StorageClass, so a
+	// cluster that never enables it doesn't need to provide one.
+	//nolint:tagliatelle // matches the KMS_PROVIDER-style keys used by the kms-config ConfigMap
+	kmsConfigFile := util.FromEnv("SPDKCSI_KMS_CONFIG", "/etc/spdkcsi-kms-config/config.json")
+	if _, statErr := os.Stat(kmsConfigFile); statErr == nil {
+		var kmsConfigs map[string]util.KMSConfig
+		if err := util.ParseJSONFile(kmsConfigFile, &kmsConfigs); err != nil {
+			return nil, fmt.Errorf("failed to parse kms-config %s: %w", kmsConfigFile, err)
+		}
+		server.kmsConfigs = kmsConfigs
+	}
 
 	// get spdk node secrets, see deploy/kubernetes/secret.yaml
 	//nolint:tagliatelle // not using json:snake case
@@ -395,16 +780,16 @@ func newControllerServer(d *csicommon.CSIDriver) (*controllerServer, error) {
 				tokenFound = true
 				spdkNode, err := util.NewSpdkNode(node.URL, token.UserName, token.Password, node.TargetType, node.TargetAddr)
 				if err != nil {
-					klog.Errorf("failed to create spdk node %s: %s", node.Name, err.Error())
+					log.DefaultLog("failed to create spdk node %s: %s", node.Name, err.Error())
 				} else {
-					klog.Infof("spdk node created: name=%s, url=%s", node.Name, node.URL)
+					log.DefaultLog("spdk node created: name=%s, url=%s", node.Name, node.URL)
 					server.spdkNodes[node.Name] = spdkNode
 				}
 				break
 			}
 		}
 		if !tokenFound {
-			klog.Errorf("failed to find secret for spdk node %s", node.Name)
+			log.DefaultLog("failed to find secret for spdk node %s", node.Name)
 		}
 	}
 	if len(server.spdkNodes) == 0 {
@@ -0,0 +1,125 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spdk
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+
+	csicommon "github.com/spdk/spdk-csi/pkg/csi-common"
+	"github.com/spdk/spdk-csi/pkg/util/log"
+)
+
+const driverName = "csi.spdk.io"
+
+// DriverMode selects which CSI services a process's gRPC endpoint serves.
+// Splitting the controller and node plugins into separate binaries means the
+// controller Deployment no longer needs privileged/host-mount NVMe-oF
+// tooling, and the node DaemonSet no longer needs cluster-wide SPDK RPC
+// credentials.
+type DriverMode string
+
+const (
+	ControllerMode DriverMode = "controller"
+	NodeMode       DriverMode = "node"
+	// AllMode runs both the controller and node services in one process, for
+	// backward compatibility with the combined spdkcsi binary.
+	AllMode DriverMode = "all"
+)
+
+// Run starts the CSI gRPC server at endpoint, serving only the services
+// implied by mode.
+func Run(mode DriverMode, driverVersion, nodeID, endpoint string) error {
+	csiDriver := csicommon.NewCSIDriver(driverName, driverVersion, nodeID)
+	csiDriver.AddVolumeCapabilityAccessModes([]csi.VolumeCapability_AccessMode_Mode{
+		csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+	})
+
+	var controllerServerImpl csi.ControllerServer
+	var nodeServerImpl csi.NodeServer
+
+	switch mode {
+	case ControllerMode:
+		cs, err := newControllerServer(csiDriver)
+		if err != nil {
+			return fmt.Errorf("failed to create controller server: %w", err)
+		}
+		controllerServerImpl = cs
+	case NodeMode:
+		nodeServerImpl = newNodeServer(csiDriver, nodeID)
+	case AllMode:
+		cs, err := newControllerServer(csiDriver)
+		if err != nil {
+			return fmt.Errorf("failed to create controller server: %w", err)
+		}
+		controllerServerImpl = cs
+		nodeServerImpl = newNodeServer(csiDriver, nodeID)
+	default:
+		return fmt.Errorf("unknown drivermode %q, must be one of controller, node, all", mode)
+	}
+
+	network, addr, err := parseEndpoint(endpoint)
+	if err != nil {
+		return err
+	}
+	if network == "unix" {
+		if rmErr := os.Remove(addr); rmErr != nil && !os.IsNotExist(rmErr) {
+			return fmt.Errorf("failed to remove stale socket %s: %w", addr, rmErr)
+		}
+	}
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", endpoint, err)
+	}
+
+	// the audit interceptor replaces the ad-hoc klog calls duplicated across
+	// every RPC handler with one structured log line per call.
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(auditUnaryInterceptor()))
+	csi.RegisterIdentityServer(grpcServer, csicommon.NewDefaultIdentityServer(csiDriver))
+	if controllerServerImpl != nil {
+		csi.RegisterControllerServer(grpcServer, controllerServerImpl)
+	}
+	if nodeServerImpl != nil {
+		csi.RegisterNodeServer(grpcServer, nodeServerImpl)
+	}
+
+	log.DefaultLog("listening for CSI requests on %s", endpoint)
+	return grpcServer.Serve(listener)
+}
+
+// parseEndpoint splits a CSI endpoint of the form "unix:///path/to.sock" or
+// "tcp://host:port" into the network and address net.Listen expects.
+func parseEndpoint(endpoint string) (network, addr string, err error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "unix":
+		return "unix", u.Host + u.Path, nil
+	case "tcp":
+		return "tcp", u.Host, nil
+	default:
+		return "", "", fmt.Errorf("unsupported endpoint scheme %q in %q", u.Scheme, endpoint)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spdk
+
+import (
+	"fmt"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/spdk/spdk-csi/pkg/util"
+)
+
+const defaultMetadataConfigMapName = "spdkcsi-metadata"
+
+// newMetadataStore resolves the configured metadata store backend. jsonFilePath
+// is only meaningful for storeType "jsonfile", which exists for tests rather
+// than production use.
+func newMetadataStore(storeType, configMapName, configMapNamespace string, etcdEndpoints []string, jsonFilePath string) (util.MetadataStore, error) {
+	switch storeType {
+	case "", "configmap":
+		cfg, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load in-cluster config for metadata configmap: %w", err)
+		}
+		client, err := kubernetes.NewForConfig(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create kubernetes client for metadata configmap: %w", err)
+		}
+		name := configMapName
+		if name == "" {
+			name = defaultMetadataConfigMapName
+		}
+		namespace := configMapNamespace
+		if namespace == "" {
+			namespace = util.FromEnv("POD_NAMESPACE", "default")
+		}
+		return util.NewConfigMapMetadataStore(client, namespace, name)
+	case "etcd":
+		if len(etcdEndpoints) == 0 {
+			return nil, fmt.Errorf("metadataStore.etcdEndpoints must be set when metadataStore.type is \"etcd\"")
+		}
+		client, err := clientv3.New(clientv3.Config{Endpoints: etcdEndpoints})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create etcd client: %w", err)
+		}
+		return util.NewEtcdMetadataStore(client, "/spdkcsi/volumes/"), nil
+	case "jsonfile":
+		if jsonFilePath == "" {
+			return nil, fmt.Errorf("metadataStore.jsonFilePath must be set when metadataStore.type is \"jsonfile\"")
+		}
+		return util.NewJSONFileMetadataStore(jsonFilePath)
+	default:
+		return nil, fmt.Errorf("unknown metadata store type %q", storeType)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spdk
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog"
+	"k8s.io/utils/exec"
+	"k8s.io/utils/mount"
+
+	csicommon "github.com/spdk/spdk-csi/pkg/csi-common"
+)
+
+type nodeServer struct {
+	*csicommon.DefaultNodeServer
+	// nodeID identifies which SPDK node this Kubernetes node talks to; it is
+	// advertised via NodeGetInfo as the topology.spdk.csi/node segment so the
+	// controller's scheduler can honor topology-constrained placement.
+	// It must be set (via --nodeid) to the exact SPDK node name used as the
+	// key in the controller's spdkNodes map, i.e. one of config.json's
+	// Nodes[].name entries - the two are looked up by direct string match.
+	nodeID string
+}
+
+func newNodeServer(d *csicommon.CSIDriver, nodeID string) *nodeServer {
+	return &nodeServer{
+		DefaultNodeServer: csicommon.NewDefaultNodeServer(d),
+		nodeID:            nodeID,
+	}
+}
+
+// NodeGetInfo advertises this node's SPDK node ID both as the CSI NodeId and
+// as the topology.spdk.csi/node accessible-topology segment, so that
+// CreateVolume requests carrying a topology constraint (see
+// scheduleRequestFromCSI) actually get scheduled onto a matching node.
+func (ns *nodeServer) NodeGetInfo(_ context.Context, _ *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
+	return &csi.NodeGetInfoResponse{
+		NodeId: ns.nodeID,
+		AccessibleTopology: &csi.Topology{
+			Segments: map[string]string{
+				topologyNodeKey: ns.nodeID,
+			},
+		},
+	}, nil
+}
+
+// NodeExpandVolume rescans the underlying NVMe-oF/iSCSI block device so the
+// kernel picks up the new size reported by ControllerExpandVolume, then grows
+// the filesystem on top of it to match.
+func (ns *nodeServer) NodeExpandVolume(_ context.Context, req *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
+	volumePath := req.GetVolumePath()
+	if volumePath == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume path missing in request")
+	}
+
+	devicePath, _, err := mount.GetDeviceNameFromMount(mount.New(""), volumePath)
+	if err != nil {
+		klog.Errorf("failed to find device for volume path %s: %v", volumePath, err)
+		return nil, status.Errorf(codes.Internal, "failed to find device for volume path %s: %v", volumePath, err)
+	}
+
+	if err := rescanDevice(devicePath); err != nil {
+		klog.Errorf("failed to rescan device %s: %v", devicePath, err)
+		return nil, status.Errorf(codes.Internal, "failed to rescan device %s: %v", devicePath, err)
+	}
+
+	resizer := mount.NewResizeFs(exec.New())
+	if _, err := resizer.Resize(devicePath, volumePath); err != nil {
+		klog.Errorf("failed to resize filesystem on %s: %v", devicePath, err)
+		return nil, status.Errorf(codes.Internal, "failed to resize filesystem on %s: %v", devicePath, err)
+	}
+
+	return &csi.NodeExpandVolumeResponse{}, nil
+}
+
+// rescanDevice triggers a kernel rescan of an NVMe-oF/iSCSI block device so
+// it picks up the new size after the lvol has been grown on the SPDK target.
+func rescanDevice(devicePath string) error {
+	devName := filepath.Base(devicePath)
+	rescanPath := fmt.Sprintf("/sys/class/block/%s/device/rescan", devName)
+	if err := os.WriteFile(rescanPath, []byte("1"), 0o200); err != nil {
+		return fmt.Errorf("writing to %s: %w", rescanPath, err)
+	}
+	return nil
+}
+
+func (ns *nodeServer) NodeGetCapabilities(_ context.Context, _ *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
+	return &csi.NodeGetCapabilitiesResponse{
+		Capabilities: []*csi.NodeServiceCapability{
+			{
+				Type: &csi.NodeServiceCapability_Rpc{
+					Rpc: &csi.NodeServiceCapability_RPC{Type: csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME},
+				},
+			},
+			{
+				Type: &csi.NodeServiceCapability_Rpc{
+					Rpc: &csi.NodeServiceCapability_RPC{Type: csi.NodeServiceCapability_RPC_EXPAND_VOLUME},
+				},
+			},
+		},
+	}, nil
+}
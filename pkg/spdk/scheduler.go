@@ -0,0 +1,142 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spdk
+
+import (
+	"fmt"
+
+	"k8s.io/klog"
+
+	"github.com/spdk/spdk-csi/pkg/util"
+)
+
+// topologyNodeKey is the CSI topology segment key the node plugin populates to
+// advertise which SPDK node a Kubernetes node is wired up to.
+const topologyNodeKey = "topology.spdk.csi/node"
+
+// maxScheduleRetries bounds how many times createVolume will blacklist a
+// lvstore and re-invoke the scheduler after an ErrJSONNoSpaceLeft race.
+const maxScheduleRetries = 3
+
+// scheduleRequest bundles the placement constraints a Scheduler must honor.
+type scheduleRequest struct {
+	sizeMiB int64
+	// node, if non-empty, restricts placement to this SPDK node, derived from
+	// the CSI topology.spdk.csi/node accessibility requirement.
+	node string
+	// pool, if non-empty, restricts placement to this lvstore, from the
+	// "pool"/"lvstore" StorageClass parameter.
+	pool string
+	// blacklist holds "nodeName:lvstoreName" pairs to skip, populated after a
+	// concurrent-allocation failure so the caller can retry elsewhere.
+	blacklist map[string]bool
+}
+
+func (r *scheduleRequest) isBlacklisted(nodeName, lvstore string) bool {
+	return r.blacklist[fmt.Sprintf("%s:%s", nodeName, lvstore)]
+}
+
+// Scheduler picks the SPDK node/lvstore that should host a new volume.
+type Scheduler interface {
+	// Schedule returns the chosen node name and lvstore name, or an error if
+	// no lvstore satisfies req.
+	Schedule(spdkNodes map[string]util.SpdkNode, req scheduleRequest) (nodeName, lvstore string, err error)
+}
+
+// eligibleLvstores walks every node/lvstore combination, applying the node,
+// pool, and blacklist constraints shared by every Scheduler implementation.
+func eligibleLvstores(spdkNodes map[string]util.SpdkNode, req scheduleRequest, consider func(nodeName string, lvstore *util.LvStore)) error {
+	for nodeName, spdkNode := range spdkNodes {
+		if req.node != "" && req.node != nodeName {
+			continue
+		}
+		lvstores, err := spdkNode.LvStores()
+		if err != nil {
+			klog.Errorf("failed to get lvstores from node %s: %s", spdkNode.Info(), err.Error())
+			continue
+		}
+		for i := range lvstores {
+			lvstore := &lvstores[i]
+			if req.pool != "" && req.pool != lvstore.Name {
+				continue
+			}
+			if req.isBlacklisted(nodeName, lvstore.Name) {
+				continue
+			}
+			if lvstore.FreeSizeMiB <= req.sizeMiB {
+				continue
+			}
+			consider(nodeName, lvstore)
+		}
+	}
+	return nil
+}
+
+// bestFitScheduler picks the lvstore whose remaining free space after
+// allocation is smallest but still positive, packing small PVCs tightly to
+// reduce fragmentation across the fleet.
+type bestFitScheduler struct{}
+
+func (bestFitScheduler) Schedule(spdkNodes map[string]util.SpdkNode, req scheduleRequest) (nodeName, lvstore string, err error) {
+	bestRemaining := int64(-1)
+	_ = eligibleLvstores(spdkNodes, req, func(candidateNode string, ls *util.LvStore) {
+		remaining := ls.FreeSizeMiB - req.sizeMiB
+		if bestRemaining == -1 || remaining < bestRemaining {
+			bestRemaining = remaining
+			nodeName = candidateNode
+			lvstore = ls.Name
+		}
+	})
+	if lvstore == "" {
+		return "", "", fmt.Errorf("best-fit scheduler: failed to find node with enough free space")
+	}
+	return nodeName, lvstore, nil
+}
+
+// maxFreeScheduler picks the node/lvstore with the most free space, spreading
+// large volumes and bandwidth-heavy workloads across the fleet instead of
+// packing them onto one node.
+type maxFreeScheduler struct{}
+
+func (maxFreeScheduler) Schedule(spdkNodes map[string]util.SpdkNode, req scheduleRequest) (nodeName, lvstore string, err error) {
+	maxFree := int64(-1)
+	_ = eligibleLvstores(spdkNodes, req, func(candidateNode string, ls *util.LvStore) {
+		if ls.FreeSizeMiB > maxFree {
+			maxFree = ls.FreeSizeMiB
+			nodeName = candidateNode
+			lvstore = ls.Name
+		}
+	})
+	if lvstore == "" {
+		return "", "", fmt.Errorf("max-free scheduler: failed to find node with enough free space")
+	}
+	return nodeName, lvstore, nil
+}
+
+// newScheduler resolves the configured scheduler strategy name, falling back
+// to best-fit when empty or unrecognized.
+func newScheduler(strategy string) Scheduler {
+	switch strategy {
+	case "max-free":
+		return maxFreeScheduler{}
+	case "", "best-fit":
+		return bestFitScheduler{}
+	default:
+		klog.Warningf("unknown scheduler strategy %q, defaulting to best-fit", strategy)
+		return bestFitScheduler{}
+	}
+}
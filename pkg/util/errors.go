@@ -0,0 +1,35 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import "errors"
+
+var (
+	// ErrVolumeUnpublished is returned when an operation expects a published
+	// volume but the volume has already been unpublished.
+	ErrVolumeUnpublished = errors.New("volume not published")
+	// ErrVolumeDeleted is returned when an operation expects an existing
+	// volume but the volume has already been deleted.
+	ErrVolumeDeleted = errors.New("volume already deleted")
+	// ErrJSONNoSuchDevice mirrors SPDK's "No such device" JSON-RPC error.
+	ErrJSONNoSuchDevice = errors.New("no such device")
+	// ErrJSONNoSpaceLeft mirrors SPDK's "No space left on device" JSON-RPC
+	// error, returned when an lvstore runs out of free space during
+	// CreateVolume/CloneVolume, typically due to a race with a concurrent
+	// allocation on the same lvstore.
+	ErrJSONNoSpaceLeft = errors.New("no space left on device")
+)
@@ -0,0 +1,81 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import "fmt"
+
+// DEKSizeBytes is the size of the data encryption key handed to SPDK's
+// bdev_crypto_create, sized for AES-256.
+const DEKSizeBytes = 32
+
+// KMS generates and manages per-volume data encryption keys (DEKs). The
+// plaintext DEK is never written to the volume metadata store; what gets
+// persisted there is the opaque keyHandle a KMS backend needs to recover it
+// later. The "secrets" backend itself stores the plaintext DEK in a
+// Kubernetes Secret.
+type KMS interface {
+	// GenerateDEK creates a fresh DEK and returns it along with a handle that
+	// can later be passed to GetDEK/DestroyDEK.
+	GenerateDEK() (keyHandle string, dek []byte, err error)
+	// GetDEK returns the plaintext DEK for a previously generated handle, used
+	// when CloneVolume needs the same key as its source volume.
+	GetDEK(keyHandle string) ([]byte, error)
+	// DestroyDEK releases the DEK so it can no longer be recovered, called
+	// from DeleteVolume.
+	DestroyDEK(keyHandle string) error
+}
+
+// KMSConfig is one entry from the kms-config ConfigMap (see
+// deploy/kubernetes/kms-config.yaml), keyed by the StorageClass's
+// encryptionKMSID parameter.
+//
+//nolint:tagliatelle // matches the KMS_PROVIDER-style keys used by the kms-config ConfigMap
+type KMSConfig struct {
+	Provider string `json:"KMS_PROVIDER"`
+
+	// "secrets" (Kubernetes Secrets) backend
+	SecretNamespace string `json:"SECRET_NAMESPACE"`
+
+	// "vault" (HashiCorp Vault Transit) backend
+	VaultAddress    string `json:"VAULT_ADDR"`
+	VaultTransitKey string `json:"VAULT_TRANSIT_KEY"`
+	VaultToken      string `json:"VAULT_TOKEN"`
+
+	// "aws-kms" backend
+	AWSRegion string `json:"AWS_REGION"`
+	AWSCMKID  string `json:"AWS_CMK_ID"`
+}
+
+// NewKMS resolves a KMS backend from the config loaded from the kms-config
+// ConfigMap, keyed by kmsID (the StorageClass's encryptionKMSID parameter).
+func NewKMS(kmsID string, configs map[string]KMSConfig) (KMS, error) {
+	cfg, ok := configs[kmsID]
+	if !ok {
+		return nil, fmt.Errorf("no kms-config entry found for encryptionKMSID %q", kmsID)
+	}
+
+	switch cfg.Provider {
+	case "secrets":
+		return newSecretsKMS(cfg)
+	case "vault":
+		return newVaultKMS(cfg)
+	case "aws-kms":
+		return newAWSKMS(cfg)
+	default:
+		return nil, fmt.Errorf("unknown KMS_PROVIDER %q for encryptionKMSID %q", cfg.Provider, kmsID)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// awsKMS uses AWS KMS's GenerateDataKey to mint DEKs under a customer master
+// key (CMK). Like vaultKMS, the keyHandle is the KMS-encrypted ciphertext
+// blob of the DEK, so DestroyDEK is a no-op.
+type awsKMS struct {
+	client *kms.KMS
+	cmkID  string
+}
+
+func newAWSKMS(cfg KMSConfig) (KMS, error) {
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(cfg.AWSRegion)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aws session: %w", err)
+	}
+	if cfg.AWSCMKID == "" {
+		return nil, fmt.Errorf("AWS_CMK_ID must be set for aws-kms provider")
+	}
+	return &awsKMS{client: kms.New(sess), cmkID: cfg.AWSCMKID}, nil
+}
+
+func (k *awsKMS) GenerateDEK() (string, []byte, error) {
+	out, err := k.client.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:   aws.String(k.cmkID),
+		KeySpec: aws.String("AES_256"),
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate data key from aws kms key %s: %w", k.cmkID, err)
+	}
+	return base64.StdEncoding.EncodeToString(out.CiphertextBlob), out.Plaintext, nil
+}
+
+func (k *awsKMS) GetDEK(keyHandle string) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(keyHandle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key handle: %w", err)
+	}
+	out, err := k.client.Decrypt(&kms.DecryptInput{
+		KeyId:          aws.String(k.cmkID),
+		CiphertextBlob: ciphertext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key from aws kms key %s: %w", k.cmkID, err)
+	}
+	return out.Plaintext, nil
+}
+
+func (k *awsKMS) DestroyDEK(_ string) error {
+	return nil
+}
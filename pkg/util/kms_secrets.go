@@ -0,0 +1,94 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// secretsKMS stores each DEK in its own Kubernetes Secret, named
+// "spdkcsi-dek-<keyHandle>" in the configured namespace, keyHandle being a
+// random hex ID unrelated to the DEK value itself.
+type secretsKMS struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+func newSecretsKMS(cfg KMSConfig) (KMS, error) {
+	restCfg, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load in-cluster config for secrets KMS: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client for secrets KMS: %w", err)
+	}
+	namespace := cfg.SecretNamespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	return &secretsKMS{client: client, namespace: namespace}, nil
+}
+
+func (k *secretsKMS) secretName(keyHandle string) string {
+	return "spdkcsi-dek-" + keyHandle
+}
+
+func (k *secretsKMS) GenerateDEK() (string, []byte, error) {
+	dek := make([]byte, DEKSizeBytes)
+	if _, err := rand.Read(dek); err != nil {
+		return "", nil, fmt.Errorf("failed to generate DEK: %w", err)
+	}
+	handleBytes := make([]byte, 16)
+	if _, err := rand.Read(handleBytes); err != nil {
+		return "", nil, fmt.Errorf("failed to generate key handle: %w", err)
+	}
+	keyHandle := fmt.Sprintf("%x", handleBytes)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: k.secretName(keyHandle), Namespace: k.namespace},
+		Data:       map[string][]byte{"dek": dek},
+	}
+	if _, err := k.client.CoreV1().Secrets(k.namespace).Create(context.Background(), secret, metav1.CreateOptions{}); err != nil {
+		return "", nil, fmt.Errorf("failed to store DEK secret: %w", err)
+	}
+	return keyHandle, dek, nil
+}
+
+func (k *secretsKMS) GetDEK(keyHandle string) ([]byte, error) {
+	secret, err := k.client.CoreV1().Secrets(k.namespace).Get(context.Background(), k.secretName(keyHandle), metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DEK secret %s: %w", keyHandle, err)
+	}
+	return secret.Data["dek"], nil
+}
+
+func (k *secretsKMS) DestroyDEK(keyHandle string) error {
+	err := k.client.CoreV1().Secrets(k.namespace).Delete(context.Background(), k.secretName(keyHandle), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete DEK secret %s: %w", keyHandle, err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+// vaultKMS uses HashiCorp Vault's Transit secrets engine to generate data
+// keys. The keyHandle is the Vault-encrypted ciphertext of the DEK itself, so
+// nothing needs to be separately stored in Vault and DestroyDEK is a no-op:
+// forgetting the ciphertext (by deleting the volume's metadata record) is
+// sufficient to make the DEK unrecoverable.
+type vaultKMS struct {
+	client     *vaultapi.Client
+	transitKey string
+}
+
+func newVaultKMS(cfg KMSConfig) (KMS, error) {
+	vcfg := vaultapi.DefaultConfig()
+	vcfg.Address = cfg.VaultAddress
+	client, err := vaultapi.NewClient(vcfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vault client: %w", err)
+	}
+	client.SetToken(cfg.VaultToken)
+	if cfg.VaultTransitKey == "" {
+		return nil, fmt.Errorf("VAULT_TRANSIT_KEY must be set for vault KMS provider")
+	}
+	return &vaultKMS{client: client, transitKey: cfg.VaultTransitKey}, nil
+}
+
+func (k *vaultKMS) GenerateDEK() (string, []byte, error) {
+	secret, err := k.client.Logical().Write(fmt.Sprintf("transit/datakey/plaintext/%s", k.transitKey), nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate data key from vault transit key %s: %w", k.transitKey, err)
+	}
+	plaintextB64, _ := secret.Data["plaintext"].(string)
+	ciphertext, _ := secret.Data["ciphertext"].(string)
+	dek, err := base64.StdEncoding.DecodeString(plaintextB64)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode vault data key: %w", err)
+	}
+	return ciphertext, dek, nil
+}
+
+func (k *vaultKMS) GetDEK(keyHandle string) ([]byte, error) {
+	secret, err := k.client.Logical().Write(fmt.Sprintf("transit/decrypt/%s", k.transitKey), map[string]interface{}{
+		"ciphertext": keyHandle,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key from vault transit key %s: %w", k.transitKey, err)
+	}
+	plaintextB64, _ := secret.Data["plaintext"].(string)
+	dek, err := base64.StdEncoding.DecodeString(plaintextB64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode vault data key: %w", err)
+	}
+	return dek, nil
+}
+
+func (k *vaultKMS) DestroyDEK(_ string) error {
+	return nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package log centralizes the leveled/structured logging conventions used by
+// the controller, so every RPC handler doesn't re-decide on its own whether
+// a message is routine, verbose-only, or worth a structured audit record.
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"k8s.io/klog"
+)
+
+// DefaultLog is the standard entry point for ordinary operational messages,
+// replacing the ad-hoc klog.Errorf/klog.Warningf/klog.Infof calls that used
+// to be scattered across the controller.
+func DefaultLog(format string, args ...interface{}) {
+	klog.InfoDepth(1, fmt.Sprintf(format, args...))
+}
+
+// TraceLog is gated behind -v=4, for request/response detail that's too
+// noisy to leave on by default and is only needed when actively debugging.
+func TraceLog(format string, args ...interface{}) {
+	if klog.V(4).Enabled() {
+		klog.V(4).InfoDepth(1, fmt.Sprintf(format, args...))
+	}
+}
+
+// AuditRecord is one structured line per controller RPC, meant to answer
+// "why did this PVC end up on node X" after the fact without needing -v=4
+// turned on everywhere.
+//
+//nolint:tagliatelle // not using json:snake case
+type AuditRecord struct {
+	Method     string `json:"method"`
+	VolumeID   string `json:"volumeID,omitempty"`
+	SnapshotID string `json:"snapshotID,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Decision   string `json:"decision"`
+	LatencyMs  int64  `json:"latencyMs"`
+	Code       string `json:"code"`
+}
+
+// AuditLog emits a single structured record for a completed RPC.
+func AuditLog(r AuditRecord) {
+	raw, err := json.Marshal(r)
+	if err != nil {
+		klog.Errorf("failed to marshal audit record: %v", err)
+		return
+	}
+	klog.InfoDepth(1, "audit "+string(raw))
+}
+
+// RedactRequest renders req as JSON with the secrets/volumeContext/
+// parameters fields stripped, matching what csi-lib-utils' protosanitizer
+// does, so a TraceLog of the full request never leaks credentials or
+// user-supplied volume context into logs.
+func RedactRequest(req proto.Message) string {
+	raw, err := protojson.Marshal(req)
+	if err != nil {
+		return fmt.Sprintf("<failed to marshal request: %v>", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return string(raw)
+	}
+	for _, key := range []string{"secrets", "volumeContext", "parameters"} {
+		if _, ok := data[key]; ok {
+			data[key] = "***stripped***"
+		}
+	}
+	redacted, err := json.Marshal(data)
+	if err != nil {
+		return string(raw)
+	}
+	return string(redacted)
+}
+
+type decisionKeyType struct{}
+
+var decisionKey decisionKeyType
+
+// decisionHolder is stored by pointer so a decision attached early via
+// WithDecision can be overridden later by SetDecision on the same ctx,
+// without every caller needing to re-wrap and pass a new context down.
+type decisionHolder struct{ value string }
+
+// WithDecision attaches a mutable audit decision (e.g. "created",
+// "found-existing") to ctx, defaulting to decision. A unary interceptor
+// reads it back after the handler returns to fill in AuditRecord.Decision;
+// the handler itself may override it via SetDecision.
+func WithDecision(ctx context.Context, decision string) context.Context {
+	return context.WithValue(ctx, decisionKey, &decisionHolder{value: decision})
+}
+
+// SetDecision overrides the audit decision previously attached to ctx via
+// WithDecision. It is a no-op if ctx carries no decision holder.
+func SetDecision(ctx context.Context, decision string) {
+	if h, ok := ctx.Value(decisionKey).(*decisionHolder); ok {
+		h.value = decision
+	}
+}
+
+// DecisionFromContext returns the audit decision attached to ctx, if any.
+func DecisionFromContext(ctx context.Context) (string, bool) {
+	h, ok := ctx.Value(decisionKey).(*decisionHolder)
+	if !ok {
+		return "", false
+	}
+	return h.value, true
+}
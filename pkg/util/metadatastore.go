@@ -0,0 +1,68 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import "errors"
+
+// ErrMetadataNotFound is returned by MetadataStore.Get when no record exists
+// for the given CSI volume ID, e.g. because the volume predates the
+// metadata store and still uses the legacy "node:lvol" VolumeID format.
+var ErrMetadataNotFound = errors.New("volume metadata not found")
+
+// VolumeMetadata is the persisted record mapping an opaque CSI VolumeID to
+// the SPDK node/lvstore/lvol that actually backs it.
+//
+//nolint:tagliatelle // not using json:snake case
+type VolumeMetadata struct {
+	// Name is the CSI CreateVolumeRequest.Name (the PV's requested name),
+	// kept so a retried CreateVolume can look up the original opaque
+	// VolumeID by name instead of re-deriving a different one.
+	Name           string `json:"name,omitempty"`
+	NodeName       string `json:"nodeName"`
+	LvStore        string `json:"lvstore"`
+	LvolUUID       string `json:"lvolUUID"`
+	SizeMiB        int64  `json:"sizeMiB"`
+	SourceSnapshot string `json:"sourceSnapshot,omitempty"`
+
+	// Encrypted, KMSID, KeyHandle, and CryptoBdevName are set when the volume
+	// (or, for a record keyed by SnapshotId, the snapshot's source volume)
+	// was created with encryption enabled. KeyHandle is the opaque handle a
+	// KMS backend needs to recover the DEK; CryptoBdevName is the SPDK crypto
+	// bdev layered over the lvol, which is what gets published in place of
+	// the raw lvol, and is deleted before the raw lvol is.
+	Encrypted      bool   `json:"encrypted,omitempty"`
+	KMSID          string `json:"kmsID,omitempty"`
+	KeyHandle      string `json:"keyHandle,omitempty"`
+	CryptoBdevName string `json:"cryptoBdevName,omitempty"`
+}
+
+// MetadataStore persists the csiVolumeID -> VolumeMetadata mapping so that
+// CSI VolumeIDs can be opaque UUIDs instead of encoding SPDK topology.
+type MetadataStore interface {
+	// Create persists metadata for a newly created volume. It returns an
+	// error if an entry for csiVolumeID already exists.
+	Create(csiVolumeID string, meta VolumeMetadata) error
+	// Get returns ErrMetadataNotFound if no entry exists for csiVolumeID.
+	Get(csiVolumeID string) (VolumeMetadata, error)
+	// Delete is a no-op, returning nil, if no entry exists for csiVolumeID.
+	Delete(csiVolumeID string) error
+	// List returns every persisted record, keyed by csiVolumeID. Used for the
+	// CreateVolume idempotent-retry path (look up an existing volume by Name)
+	// and to check whether a DEK is still referenced by another volume or
+	// snapshot before destroying it.
+	List() (map[string]VolumeMetadata, error)
+}
@@ -0,0 +1,129 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// configMapMetadataStore persists one ConfigMap entry per volume in a single
+// namespaced ConfigMap, keyed by csiVolumeID, with the VolumeMetadata encoded
+// as JSON. Updates go through the usual read-modify-write-with-retry pattern
+// since ConfigMaps offer no per-key API.
+type configMapMetadataStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewConfigMapMetadataStore returns a MetadataStore backed by the named
+// ConfigMap, created on first use if it does not already exist.
+func NewConfigMapMetadataStore(client kubernetes.Interface, namespace, name string) (MetadataStore, error) {
+	ctx := context.Background()
+	_, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string]string{},
+		}
+		if _, err := client.CoreV1().ConfigMaps(namespace).Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+			return nil, fmt.Errorf("failed to create metadata configmap %s/%s: %w", namespace, name, err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get metadata configmap %s/%s: %w", namespace, name, err)
+	}
+	return &configMapMetadataStore{client: client, namespace: namespace, name: name}, nil
+}
+
+func (s *configMapMetadataStore) update(mutate func(data map[string]string) error) error {
+	ctx := context.Background()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get metadata configmap %s/%s: %w", s.namespace, s.name, err)
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		if err := mutate(cm.Data); err != nil {
+			return err
+		}
+		_, err = s.client.CoreV1().ConfigMaps(s.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func (s *configMapMetadataStore) Create(csiVolumeID string, meta VolumeMetadata) error {
+	return s.update(func(data map[string]string) error {
+		if _, ok := data[csiVolumeID]; ok {
+			return fmt.Errorf("metadata already exists for volume %s", csiVolumeID)
+		}
+		raw, err := json.Marshal(meta)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata for volume %s: %w", csiVolumeID, err)
+		}
+		data[csiVolumeID] = string(raw)
+		return nil
+	})
+}
+
+func (s *configMapMetadataStore) Get(csiVolumeID string) (VolumeMetadata, error) {
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(context.Background(), s.name, metav1.GetOptions{})
+	if err != nil {
+		return VolumeMetadata{}, fmt.Errorf("failed to get metadata configmap %s/%s: %w", s.namespace, s.name, err)
+	}
+	raw, ok := cm.Data[csiVolumeID]
+	if !ok {
+		return VolumeMetadata{}, ErrMetadataNotFound
+	}
+	var meta VolumeMetadata
+	if err := json.Unmarshal([]byte(raw), &meta); err != nil {
+		return VolumeMetadata{}, fmt.Errorf("failed to parse metadata for volume %s: %w", csiVolumeID, err)
+	}
+	return meta, nil
+}
+
+func (s *configMapMetadataStore) Delete(csiVolumeID string) error {
+	return s.update(func(data map[string]string) error {
+		delete(data, csiVolumeID)
+		return nil
+	})
+}
+
+func (s *configMapMetadataStore) List() (map[string]VolumeMetadata, error) {
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(context.Background(), s.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata configmap %s/%s: %w", s.namespace, s.name, err)
+	}
+	records := make(map[string]VolumeMetadata, len(cm.Data))
+	for csiVolumeID, raw := range cm.Data {
+		var meta VolumeMetadata
+		if err := json.Unmarshal([]byte(raw), &meta); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata for volume %s: %w", csiVolumeID, err)
+		}
+		records[csiVolumeID] = meta
+	}
+	return records, nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// etcdMetadataStore persists each volume's metadata under keyPrefix+csiVolumeID.
+type etcdMetadataStore struct {
+	client    *clientv3.Client
+	keyPrefix string
+}
+
+// NewEtcdMetadataStore returns a MetadataStore backed by etcd, storing keys
+// under keyPrefix (e.g. "/spdkcsi/volumes/").
+func NewEtcdMetadataStore(client *clientv3.Client, keyPrefix string) MetadataStore {
+	return &etcdMetadataStore{client: client, keyPrefix: keyPrefix}
+}
+
+func (s *etcdMetadataStore) key(csiVolumeID string) string {
+	return s.keyPrefix + csiVolumeID
+}
+
+func (s *etcdMetadataStore) Create(csiVolumeID string, meta VolumeMetadata) error {
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata for volume %s: %w", csiVolumeID, err)
+	}
+	key := s.key(csiVolumeID)
+	ctx := context.Background()
+	// use a transaction so concurrent Create calls for the same volume ID
+	// don't silently overwrite each other: the later one fails instead.
+	txnResp, err := s.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(raw))).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("failed to write metadata for volume %s: %w", csiVolumeID, err)
+	}
+	if !txnResp.Succeeded {
+		return fmt.Errorf("metadata already exists for volume %s", csiVolumeID)
+	}
+	return nil
+}
+
+func (s *etcdMetadataStore) Get(csiVolumeID string) (VolumeMetadata, error) {
+	resp, err := s.client.Get(context.Background(), s.key(csiVolumeID))
+	if err != nil {
+		return VolumeMetadata{}, fmt.Errorf("failed to read metadata for volume %s: %w", csiVolumeID, err)
+	}
+	if len(resp.Kvs) == 0 {
+		return VolumeMetadata{}, ErrMetadataNotFound
+	}
+	var meta VolumeMetadata
+	if err := json.Unmarshal(resp.Kvs[0].Value, &meta); err != nil {
+		return VolumeMetadata{}, fmt.Errorf("failed to parse metadata for volume %s: %w", csiVolumeID, err)
+	}
+	return meta, nil
+}
+
+func (s *etcdMetadataStore) Delete(csiVolumeID string) error {
+	_, err := s.client.Delete(context.Background(), s.key(csiVolumeID))
+	if err != nil {
+		return fmt.Errorf("failed to delete metadata for volume %s: %w", csiVolumeID, err)
+	}
+	return nil
+}
+
+func (s *etcdMetadataStore) List() (map[string]VolumeMetadata, error) {
+	resp, err := s.client.Get(context.Background(), s.keyPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list metadata under %s: %w", s.keyPrefix, err)
+	}
+	records := make(map[string]VolumeMetadata, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		csiVolumeID := strings.TrimPrefix(string(kv.Key), s.keyPrefix)
+		var meta VolumeMetadata
+		if err := json.Unmarshal(kv.Value, &meta); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata for volume %s: %w", csiVolumeID, err)
+		}
+		records[csiVolumeID] = meta
+	}
+	return records, nil
+}
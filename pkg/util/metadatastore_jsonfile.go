@@ -0,0 +1,116 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// jsonFileMetadataStore is a MetadataStore backed by a single JSON file on
+// disk. It is not suitable for production (no locking across processes,
+// whole-file rewrite on every change) and exists so unit tests can exercise
+// the metadata-store-backed code paths without a Kubernetes or etcd cluster.
+type jsonFileMetadataStore struct {
+	path string
+	mtx  sync.Mutex
+}
+
+// NewJSONFileMetadataStore returns a MetadataStore that persists to path,
+// creating an empty store if path does not yet exist.
+func NewJSONFileMetadataStore(path string) (MetadataStore, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.WriteFile(path, []byte("{}"), 0o600); err != nil {
+			return nil, fmt.Errorf("failed to initialize metadata store at %s: %w", path, err)
+		}
+	}
+	return &jsonFileMetadataStore{path: path}, nil
+}
+
+func (s *jsonFileMetadataStore) load() (map[string]VolumeMetadata, error) {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata store %s: %w", s.path, err)
+	}
+	records := map[string]VolumeMetadata{}
+	if err := json.Unmarshal(raw, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata store %s: %w", s.path, err)
+	}
+	return records, nil
+}
+
+func (s *jsonFileMetadataStore) save(records map[string]VolumeMetadata) error {
+	raw, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata store: %w", err)
+	}
+	if err := os.WriteFile(s.path, raw, 0o600); err != nil {
+		return fmt.Errorf("failed to write metadata store %s: %w", s.path, err)
+	}
+	return nil
+}
+
+func (s *jsonFileMetadataStore) Create(csiVolumeID string, meta VolumeMetadata) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	records, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := records[csiVolumeID]; ok {
+		return fmt.Errorf("metadata already exists for volume %s", csiVolumeID)
+	}
+	records[csiVolumeID] = meta
+	return s.save(records)
+}
+
+func (s *jsonFileMetadataStore) Get(csiVolumeID string) (VolumeMetadata, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	records, err := s.load()
+	if err != nil {
+		return VolumeMetadata{}, err
+	}
+	meta, ok := records[csiVolumeID]
+	if !ok {
+		return VolumeMetadata{}, ErrMetadataNotFound
+	}
+	return meta, nil
+}
+
+func (s *jsonFileMetadataStore) Delete(csiVolumeID string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	records, err := s.load()
+	if err != nil {
+		return err
+	}
+	delete(records, csiVolumeID)
+	return s.save(records)
+}
+
+func (s *jsonFileMetadataStore) List() (map[string]VolumeMetadata, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.load()
+}
@@ -0,0 +1,61 @@
+/*
+Copyright (c) Arm Limited and Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// VolumeOperationAlreadyExistsFmt is the message format used when a caller tries to
+// acquire a lock for an ID that already has an operation in flight.
+const VolumeOperationAlreadyExistsFmt = "an operation with the given volume %s already exists"
+
+// VolumeLocks tracks the set of volume/snapshot IDs that currently have an
+// operation in flight, so callers can fail fast instead of blocking when a
+// second operation comes in for the same ID.
+type VolumeLocks struct {
+	locks sets.String
+	mtx   sync.Mutex
+}
+
+func NewVolumeLocks() *VolumeLocks {
+	return &VolumeLocks{
+		locks: sets.NewString(),
+	}
+}
+
+// TryAcquire tries to acquire the lock for operating on the given id and
+// returns true if successful. If another operation is already using the id,
+// it returns false immediately without blocking.
+func (vl *VolumeLocks) TryAcquire(id string) bool {
+	vl.mtx.Lock()
+	defer vl.mtx.Unlock()
+	if vl.locks.Has(id) {
+		return false
+	}
+	vl.locks.Insert(id)
+	return true
+}
+
+// Release releases the lock for operating on the given id.
+func (vl *VolumeLocks) Release(id string) {
+	vl.mtx.Lock()
+	defer vl.mtx.Unlock()
+	vl.locks.Delete(id)
+}